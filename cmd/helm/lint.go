@@ -17,6 +17,7 @@ limitations under the License.
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -54,6 +55,11 @@ type lintCmd struct {
 	certFile string
 	keyFile  string
 	caFile   string
+
+	verify            bool
+	keyring           string
+	requireProvenance bool
+	sbom              bool
 }
 
 func newLintCmd(out io.Writer) *cobra.Command {
@@ -81,10 +87,29 @@ func newLintCmd(out io.Writer) *cobra.Command {
 	cmd.Flags().StringVar(&l.certFile, "cert-file", "", "identify HTTPS client using this SSL certificate file")
 	cmd.Flags().StringVar(&l.keyFile, "key-file", "", "identify HTTPS client using this SSL key file")
 	cmd.Flags().StringVar(&l.caFile, "ca-file", "", "verify certificates of HTTPS-enabled servers using this CA bundle")
+	cmd.Flags().BoolVar(&l.verify, "verify", false, "verify the provenance (.prov) file of the chart before linting")
+	cmd.Flags().StringVar(&l.keyring, "keyring", defaultKeyring, "path to the keyring used for --verify and --require-provenance")
+	cmd.Flags().BoolVar(&l.requireProvenance, "require-provenance", false, "fail if the chart is not signed, implies --verify")
+	cmd.Flags().BoolVar(&l.sbom, "sbom", false, "print a CycloneDX-style SBOM of the chart and its dependencies")
 
 	return cmd
 }
 
+// defaultKeyring matches the default used by `helm package --sign` and
+// `helm verify`. It is resolved at startup since Go does not expand a
+// leading "~" the way a shell would.
+var defaultKeyring = filepath.Join(homeDir(), ".gnupg", "pubring.gpg")
+
+// homeDir returns the current user's home directory, or "" if it can't be
+// determined, in which case defaultKeyring falls back to a relative path.
+func homeDir() string {
+	h, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return h
+}
+
 var errLintNoChart = errors.New("No chart found for linting (missing Chart.yaml)")
 
 func (l *lintCmd) run() error {
@@ -101,16 +126,24 @@ func (l *lintCmd) run() error {
 		return err
 	}
 
+	verify := l.verify || l.requireProvenance
+
 	var total int
 	var failures int
 	for _, path := range l.paths {
-		if linter, err := lintChart(path, rvals, l.namespace, l.strict); err != nil {
-			fmt.Println("==> Skipping", path)
-			fmt.Println(err)
-			if err == errLintNoChart {
-				failures = failures + 1
+		linter, chartPath, cleanup, err := lintChart(path, rvals, l.namespace, l.strict)
+		func() {
+			defer cleanup()
+
+			if err != nil {
+				fmt.Println("==> Skipping", path)
+				fmt.Println(err)
+				if err == errLintNoChart {
+					failures = failures + 1
+				}
+				return
 			}
-		} else {
+
 			fmt.Println("==> Linting", path)
 
 			if len(linter.Messages) == 0 {
@@ -121,11 +154,28 @@ func (l *lintCmd) run() error {
 				fmt.Println(msg)
 			}
 
+			// Provenance files are published alongside the packaged .tgz,
+			// not the extracted directory, so verify against the original
+			// path rather than chartPath.
+			if verify {
+				if err := lint.VerifyProvenance(path, l.keyring, l.requireProvenance); err != nil {
+					fmt.Println("[ERROR] provenance:", err)
+					failures = failures + 1
+				}
+			}
+
+			if l.sbom {
+				if err := l.printSBOM(chartPath); err != nil {
+					fmt.Println("[ERROR] sbom:", err)
+					failures = failures + 1
+				}
+			}
+
 			total = total + 1
 			if linter.HighestSeverity >= lowestTolerance {
 				failures = failures + 1
 			}
-		}
+		}()
 		fmt.Println("")
 	}
 
@@ -139,30 +189,46 @@ func (l *lintCmd) run() error {
 	return nil
 }
 
-func lintChart(path string, vals []byte, namespace string, strict bool) (support.Linter, error) {
-	var chartPath string
-	linter := support.Linter{}
+func (l *lintCmd) printSBOM(path string) error {
+	sbom, err := lint.GenerateSBOM(path)
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(sbom, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(l.out, string(out))
+	return nil
+}
+
+// lintChart lints path and returns the directory the chart was linted in —
+// either path itself, or, for a packaged .tgz, the temp directory it was
+// expanded into. The caller must call the returned cleanup func once it is
+// done with that directory (e.g. after also using it for --sbom).
+func lintChart(path string, vals []byte, namespace string, strict bool) (linter support.Linter, chartPath string, cleanup func(), err error) {
+	cleanup = func() {}
 
 	if strings.HasSuffix(path, ".tgz") {
 		tempDir, err := ioutil.TempDir("", "helm-lint")
 		if err != nil {
-			return linter, err
+			return linter, "", cleanup, err
 		}
-		defer os.RemoveAll(tempDir)
+		cleanup = func() { os.RemoveAll(tempDir) }
 
 		file, err := os.Open(path)
 		if err != nil {
-			return linter, err
+			return linter, "", cleanup, err
 		}
 		defer file.Close()
 
 		if err = chartutil.Expand(tempDir, file); err != nil {
-			return linter, err
+			return linter, "", cleanup, err
 		}
 
 		lastHyphenIndex := strings.LastIndex(filepath.Base(path), "-")
 		if lastHyphenIndex <= 0 {
-			return linter, fmt.Errorf("unable to parse chart archive %q, missing '-'", filepath.Base(path))
+			return linter, "", cleanup, fmt.Errorf("unable to parse chart archive %q, missing '-'", filepath.Base(path))
 		}
 		base := filepath.Base(path)[:lastHyphenIndex]
 		chartPath = filepath.Join(tempDir, base)
@@ -172,8 +238,8 @@ func lintChart(path string, vals []byte, namespace string, strict bool) (support
 
 	// Guard: Error out of this is not a chart.
 	if _, err := os.Stat(filepath.Join(chartPath, "Chart.yaml")); err != nil {
-		return linter, errLintNoChart
+		return linter, chartPath, cleanup, errLintNoChart
 	}
 
-	return lint.All(chartPath, vals, namespace, strict), nil
+	return lint.All(chartPath, vals, namespace, strict), chartPath, cleanup, nil
 }
@@ -0,0 +1,53 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"k8s.io/helm/pkg/provenance"
+)
+
+// VerifyProvenance checks that chartPath has a valid `.prov` signature file
+// matching keyring. If requireProvenance is true, a missing `.prov` file is
+// itself a failure rather than being silently skipped.
+func VerifyProvenance(chartPath, keyring string, requireProvenance bool) error {
+	provfile := chartPath + ".prov"
+	if _, err := os.Stat(provfile); err != nil {
+		if requireProvenance {
+			return fmt.Errorf("chart %s is not signed: missing provenance file %s", filepath.Base(chartPath), provfile)
+		}
+		return nil
+	}
+
+	if keyring == "" {
+		return fmt.Errorf("--keyring is required to verify provenance file %s", provfile)
+	}
+
+	verifier, err := provenance.NewVerifier(keyring)
+	if err != nil {
+		return fmt.Errorf("loading keyring %s: %v", keyring, err)
+	}
+
+	if _, err := verifier.Verify(chartPath, provfile); err != nil {
+		return fmt.Errorf("verifying provenance of %s: %v", filepath.Base(chartPath), err)
+	}
+
+	return nil
+}
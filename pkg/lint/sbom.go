@@ -0,0 +1,119 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+
+	"k8s.io/helm/pkg/chartutil"
+)
+
+// SBOM is a minimal CycloneDX/SPDX-style manifest of a chart and its
+// subchart dependencies, enough to answer "what's in this release" from
+// Chart.yaml/Chart.lock without installing anything.
+type SBOM struct {
+	BOMFormat   string          `json:"bomFormat"`
+	SpecVersion string          `json:"specVersion"`
+	Components  []SBOMComponent `json:"components"`
+}
+
+// SBOMComponent describes a single chart or subchart dependency.
+type SBOMComponent struct {
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// Digest is the digest recorded in the lock file for a locked
+	// dependency; otherwise it is a sha256 of the chart's Chart.yaml file.
+	Digest string `json:"digest,omitempty"`
+	Repo   string `json:"repository,omitempty"`
+}
+
+// GenerateSBOM returns a manifest of chartPath and its dependencies. The
+// chart itself is listed with its name, version and a digest of its
+// Chart.yaml. When chartutil.LoadRequirementsLock finds a lock file, each
+// locked dependency is listed with the name, version, repository and digest
+// recorded there. Otherwise the subcharts loaded from charts/ are listed,
+// each with a digest of charts/<name>/Chart.yaml and no repository; a
+// subchart with no Chart.yaml at that path makes GenerateSBOM return an error.
+func GenerateSBOM(chartPath string) (*SBOM, error) {
+	c, err := chartutil.LoadDir(chartPath)
+	if err != nil {
+		return nil, err
+	}
+
+	sbom := &SBOM{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.2",
+	}
+
+	root, err := sbomComponent(chartPath, c.Metadata.Name, c.Metadata.Version, "")
+	if err != nil {
+		return nil, err
+	}
+	sbom.Components = append(sbom.Components, root)
+
+	locked, err := chartutil.LoadRequirementsLock(c)
+	if err == nil && locked != nil {
+		for _, dep := range locked.Dependencies {
+			sbom.Components = append(sbom.Components, SBOMComponent{
+				Type:    "library",
+				Name:    dep.Name,
+				Version: dep.Version,
+				Digest:  dep.Digest,
+				Repo:    dep.Repository,
+			})
+		}
+		return sbom, nil
+	}
+
+	// No lock file: fall back to the chart's already-loaded subcharts.
+	for _, dep := range c.Dependencies {
+		comp, err := sbomComponent(filepath.Join(chartPath, "charts", dep.Metadata.Name), dep.Metadata.Name, dep.Metadata.Version, "")
+		if err != nil {
+			return nil, err
+		}
+		sbom.Components = append(sbom.Components, comp)
+	}
+
+	return sbom, nil
+}
+
+func sbomComponent(chartPath, name, version, repo string) (SBOMComponent, error) {
+	digest, err := digestChartYaml(chartPath)
+	if err != nil {
+		return SBOMComponent{}, err
+	}
+	return SBOMComponent{
+		Type:    "application",
+		Name:    name,
+		Version: version,
+		Digest:  digest,
+		Repo:    repo,
+	}, nil
+}
+
+func digestChartYaml(chartPath string) (string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(chartPath, "Chart.yaml"))
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDigestChartYaml(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sbom-test")
+	if err != nil {
+		t.Fatalf("creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	chartYaml := filepath.Join(dir, "Chart.yaml")
+	if err := ioutil.WriteFile(chartYaml, []byte("name: mychart\nversion: 1.0.0\n"), 0644); err != nil {
+		t.Fatalf("writing Chart.yaml: %v", err)
+	}
+
+	digest, err := digestChartYaml(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if digest == "" {
+		t.Fatalf("expected a non-empty digest")
+	}
+	if digest[:7] != "sha256:" {
+		t.Fatalf("expected digest to be prefixed with sha256:, got %q", digest)
+	}
+
+	// Digesting the same content twice is deterministic.
+	digest2, err := digestChartYaml(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if digest != digest2 {
+		t.Fatalf("expected digesting identical content to be deterministic, got %q and %q", digest, digest2)
+	}
+
+	// Changing the content changes the digest.
+	if err := ioutil.WriteFile(chartYaml, []byte("name: mychart\nversion: 2.0.0\n"), 0644); err != nil {
+		t.Fatalf("rewriting Chart.yaml: %v", err)
+	}
+	digest3, err := digestChartYaml(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if digest3 == digest {
+		t.Fatalf("expected digest to change when Chart.yaml content changes")
+	}
+}
+
+func TestDigestChartYamlMissingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sbom-test")
+	if err != nil {
+		t.Fatalf("creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := digestChartYaml(dir); err == nil {
+		t.Fatalf("expected an error when Chart.yaml is missing")
+	}
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tiller
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// AuditDecision is the outcome recorded for an RPC in an AuditRecord.
+type AuditDecision string
+
+// Audit decisions recorded for every RPC.
+const (
+	AuditAllow AuditDecision = "allow"
+	AuditDeny  AuditDecision = "deny"
+	AuditError AuditDecision = "error"
+)
+
+// AuditRecord is the structured, one-per-RPC audit log entry.
+type AuditRecord struct {
+	Timestamp     time.Time     `json:"timestamp"`
+	Method        string        `json:"method"`
+	ClientVersion string        `json:"clientVersion,omitempty"`
+	User          string        `json:"user,omitempty"`
+	Groups        []string      `json:"groups,omitempty"`
+	PeerAddr      string        `json:"peerAddr,omitempty"`
+	Release       string        `json:"release,omitempty"`
+	Namespace     string        `json:"namespace,omitempty"`
+	ChartRef      string        `json:"chartRef,omitempty"`
+	Decision      AuditDecision `json:"decision"`
+	Reason        string        `json:"reason,omitempty"`
+	DurationMS    int64         `json:"durationMs"`
+	GRPCStatus    string        `json:"grpcStatus"`
+}
+
+// AuditSink receives AuditRecords as RPCs complete. Implementations must not
+// retain or mutate the AuditRecord passed to Write.
+type AuditSink interface {
+	Write(AuditRecord) error
+}
+
+// RedactionHook is applied to an AuditRecord before it reaches any AuditSink,
+// so that values/secrets captured via request-scoped fields aren't logged
+// verbatim. The default, when none is configured, leaves fields untouched.
+type RedactionHook func(AuditRecord) AuditRecord
+
+// AuditConfig configures audit logging on a ServerOptsFactory.
+type AuditConfig struct {
+	// Enabled turns audit logging on. When false, Tiller logs nothing beyond
+	// its ordinary operational logs.
+	Enabled bool
+
+	Sinks []AuditSink
+
+	Redact RedactionHook
+
+	// QueueSize bounds how many AuditRecords may be buffered for delivery
+	// to Sinks before record starts dropping the oldest pending one.
+	// Defaults to 1024.
+	QueueSize int
+
+	workerOnce sync.Once
+	queue      chan AuditRecord
+}
+
+// record hands r off to a background worker that writes it to every
+// configured sink, so a slow or hanging sink (e.g. a webhook collector)
+// never blocks the RPC whose defer called record.
+func (c *AuditConfig) record(r AuditRecord) {
+	if !c.Enabled {
+		return
+	}
+	if c.Redact != nil {
+		r = c.Redact(r)
+	}
+
+	c.workerOnce.Do(func() {
+		size := c.QueueSize
+		if size <= 0 {
+			size = 1024
+		}
+		c.queue = make(chan AuditRecord, size)
+		go c.drain()
+	})
+
+	select {
+	case c.queue <- r:
+	default:
+		// The queue is full; drop the oldest pending record to make room
+		// rather than block the caller.
+		select {
+		case <-c.queue:
+		default:
+		}
+		select {
+		case c.queue <- r:
+		default:
+		}
+	}
+}
+
+// drain writes queued AuditRecords to every configured sink until queue is
+// closed. It runs on a single goroutine started the first time record is
+// called, so sink writes never run concurrently with each other.
+func (c *AuditConfig) drain() {
+	for r := range c.queue {
+		for _, sink := range c.Sinks {
+			// Audit logging must never fail an RPC; sink errors are best-effort
+			// and are discarded.
+			_ = sink.Write(r)
+		}
+	}
+}
+
+// auditUnary wraps the result of a unary RPC into an AuditRecord and writes
+// it to the configured sinks. It is called from newUnaryInterceptor with the
+// context produced after authentication (so UserInfoFromContext is populated)
+// and the error, if any, returned by the handler chain.
+func (f *ServerOptsFactory) auditUnary(ctx context.Context, method string, req Request, start time.Time, err error) {
+	f.Audit.record(newAuditRecord(ctx, method, req, start, err))
+}
+
+func newAuditRecord(ctx context.Context, method string, req Request, start time.Time, err error) AuditRecord {
+	u, _ := UserInfoFromContext(ctx)
+
+	r := AuditRecord{
+		Timestamp:     start,
+		Method:        method,
+		ClientVersion: versionFromContext(ctx),
+		Release:       req.Release,
+		Namespace:     req.Namespace,
+		ChartRef:      req.ChartRef(),
+		DurationMS:    time.Since(start).Milliseconds(),
+	}
+
+	if u != nil {
+		r.User = u.Name
+		r.Groups = u.Groups
+	}
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		r.PeerAddr = p.Addr.String()
+	}
+
+	if err != nil {
+		st, ok := status.FromError(err)
+		if ok {
+			r.GRPCStatus = st.Code().String()
+			r.Reason = st.Message()
+		} else {
+			r.GRPCStatus = "Unknown"
+			r.Reason = err.Error()
+		}
+		if r.GRPCStatus == "PermissionDenied" || r.GRPCStatus == "Unauthenticated" {
+			r.Decision = AuditDeny
+		} else {
+			r.Decision = AuditError
+		}
+	} else {
+		r.GRPCStatus = "OK"
+		r.Decision = AuditAllow
+	}
+
+	return r
+}
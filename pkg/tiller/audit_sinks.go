@@ -0,0 +1,169 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tiller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/syslog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// defaultWebhookTimeout bounds each webhook POST when
+// WebhookAuditSinkConfig.Timeout is unset, so a hanging collector can't
+// stall the background audit worker indefinitely.
+const defaultWebhookTimeout = 5 * time.Second
+
+// stdoutAuditSink writes one JSON record per line to os.Stdout.
+type stdoutAuditSink struct {
+	mu sync.Mutex
+}
+
+// NewStdoutAuditSink builds an AuditSink that writes to os.Stdout.
+func NewStdoutAuditSink() AuditSink {
+	return &stdoutAuditSink{}
+}
+
+func (s *stdoutAuditSink) Write(r AuditRecord) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = fmt.Fprintln(os.Stdout, string(data))
+	return err
+}
+
+// FileAuditSinkConfig configures a rotating, file-backed AuditSink.
+type FileAuditSinkConfig struct {
+	Path       string `yaml:"path"`
+	MaxSizeMB  int    `yaml:"maxSizeMB"`
+	MaxBackups int    `yaml:"maxBackups"`
+	MaxAgeDays int    `yaml:"maxAgeDays"`
+}
+
+type fileAuditSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewFileAuditSink builds an AuditSink that appends JSON records to
+// cfg.Path, rotating it once it exceeds cfg.MaxSizeMB.
+func NewFileAuditSink(cfg FileAuditSinkConfig) AuditSink {
+	return &fileAuditSink{
+		w: &lumberjack.Logger{
+			Filename:   cfg.Path,
+			MaxSize:    cfg.MaxSizeMB,
+			MaxBackups: cfg.MaxBackups,
+			MaxAge:     cfg.MaxAgeDays,
+		},
+	}
+}
+
+func (s *fileAuditSink) Write(r AuditRecord) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(data)
+	return err
+}
+
+// syslogAuditSink writes one JSON record per syslog message at LOG_INFO.
+type syslogAuditSink struct {
+	w *syslog.Writer
+}
+
+// NewSyslogAuditSink dials the local syslog daemon and returns an AuditSink
+// that forwards JSON records to it under the "tiller" tag.
+func NewSyslogAuditSink() (AuditSink, error) {
+	w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, "tiller")
+	if err != nil {
+		return nil, fmt.Errorf("audit: dialing syslog: %v", err)
+	}
+	return &syslogAuditSink{w: w}, nil
+}
+
+func (s *syslogAuditSink) Write(r AuditRecord) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return s.w.Info(string(data))
+}
+
+// WebhookAuditSinkConfig configures an AuditSink that POSTs each record as
+// JSON to an external collector.
+type WebhookAuditSinkConfig struct {
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// Timeout bounds each webhook POST. Defaults to defaultWebhookTimeout.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type webhookAuditSink struct {
+	cfg    WebhookAuditSinkConfig
+	client *http.Client
+}
+
+// NewWebhookAuditSink builds an AuditSink that POSTs each AuditRecord as JSON
+// to cfg.URL.
+func NewWebhookAuditSink(cfg WebhookAuditSinkConfig) AuditSink {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+	return &webhookAuditSink{cfg: cfg, client: &http.Client{Timeout: timeout}}
+}
+
+func (s *webhookAuditSink) Write(r AuditRecord) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.cfg.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("audit webhook %s returned status %d", s.cfg.URL, resp.StatusCode)
+	}
+	return nil
+}
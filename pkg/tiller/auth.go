@@ -0,0 +1,194 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tiller
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v2"
+)
+
+// UserInfo is the identity Tiller attaches to ctx once a caller has been
+// authenticated by one of the configured Authenticators.
+type UserInfo struct {
+	// Name is the authenticated user's identifier, e.g. a subject claim or CN.
+	Name string
+	// Groups the user belongs to, as reported by the authenticator.
+	Groups []string
+	// Extra carries authenticator-specific attributes (e.g. OIDC claims)
+	// that downstream release handlers or the authorizer may care about.
+	Extra map[string][]string
+}
+
+// Authenticator validates an incoming gRPC call and, on success, returns the
+// UserInfo that should be attached to the request context. Authenticators
+// that do not apply to the request (e.g. no credentials of the kind they
+// understand were presented) should return ok=false rather than an error so
+// that the next authenticator in the chain gets a chance to run.
+type Authenticator interface {
+	// Name identifies the authenticator, used in logs and AuthenticatorConfig.
+	Name() string
+	// Authenticate inspects ctx (and, for unary/stream specific data, method)
+	// and returns the authenticated UserInfo. ok is false when this
+	// authenticator found no credentials it understands in the request.
+	Authenticate(ctx context.Context, method string) (u *UserInfo, ok bool, err error)
+}
+
+type userInfoKey struct{}
+
+func withUserInfo(ctx context.Context, u *UserInfo) context.Context {
+	return context.WithValue(ctx, userInfoKey{}, u)
+}
+
+// UserInfoFromContext returns the UserInfo attached by the authentication
+// interceptor, if any.
+func UserInfoFromContext(ctx context.Context) (*UserInfo, bool) {
+	u, ok := ctx.Value(userInfoKey{}).(*UserInfo)
+	return u, ok
+}
+
+// AuthConfig describes the chain of Authenticators Tiller should run, in
+// order, for each incoming RPC. The first Authenticator that returns ok=true
+// wins; later ones are not consulted.
+type AuthConfig struct {
+	Authenticators []AuthenticatorConfig `yaml:"authenticators"`
+}
+
+// AuthenticatorConfig configures a single entry in the authenticator chain.
+type AuthenticatorConfig struct {
+	// Type selects the authenticator implementation: "oidc", "mtls", or "webhook".
+	Type string `yaml:"type"`
+
+	// EnableMethods, if non-empty, restricts this authenticator to the listed
+	// service methods (as returned by splitMethod). DisableMethods excludes
+	// methods even if EnableMethods would otherwise include them.
+	EnableMethods  []string `yaml:"enableMethods,omitempty"`
+	DisableMethods []string `yaml:"disableMethods,omitempty"`
+
+	OIDC    *OIDCAuthenticatorConfig    `yaml:"oidc,omitempty"`
+	MTLS    *MTLSAuthenticatorConfig    `yaml:"mtls,omitempty"`
+	Webhook *WebhookAuthenticatorConfig `yaml:"webhook,omitempty"`
+}
+
+// LoadAuthConfig reads and parses an AuthConfig from a YAML file on disk.
+func LoadAuthConfig(path string) (*AuthConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading auth config: %v", err)
+	}
+	cfg := &AuthConfig{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parsing auth config %s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// NewAuthenticatorChain builds the ordered list of Authenticators described
+// by cfg, instantiating the concrete implementation for each entry's Type.
+func NewAuthenticatorChain(cfg *AuthConfig) ([]Authenticator, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	chain := make([]Authenticator, 0, len(cfg.Authenticators))
+	for _, ac := range cfg.Authenticators {
+		a, err := newAuthenticator(ac)
+		if err != nil {
+			return nil, err
+		}
+		if len(ac.EnableMethods) > 0 || len(ac.DisableMethods) > 0 {
+			a = &methodFilteredAuthenticator{
+				Authenticator: a,
+				enable:        toSet(ac.EnableMethods),
+				disable:       toSet(ac.DisableMethods),
+			}
+		}
+		chain = append(chain, a)
+	}
+	return chain, nil
+}
+
+func newAuthenticator(ac AuthenticatorConfig) (Authenticator, error) {
+	switch ac.Type {
+	case "oidc":
+		if ac.OIDC == nil {
+			return nil, fmt.Errorf("authenticator %q requires an oidc config block", ac.Type)
+		}
+		return NewOIDCAuthenticator(*ac.OIDC)
+	case "mtls":
+		cfg := MTLSAuthenticatorConfig{}
+		if ac.MTLS != nil {
+			cfg = *ac.MTLS
+		}
+		return NewMTLSAuthenticator(cfg), nil
+	case "webhook":
+		if ac.Webhook == nil {
+			return nil, fmt.Errorf("authenticator %q requires a webhook config block", ac.Type)
+		}
+		return NewWebhookAuthenticator(*ac.Webhook)
+	default:
+		return nil, fmt.Errorf("unknown authenticator type %q", ac.Type)
+	}
+}
+
+func toSet(ss []string) map[string]bool {
+	if len(ss) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(ss))
+	for _, s := range ss {
+		set[s] = true
+	}
+	return set
+}
+
+// methodFilteredAuthenticator wraps an Authenticator so it only runs for the
+// methods selected by the enable/disable sets computed from AuthenticatorConfig.
+type methodFilteredAuthenticator struct {
+	Authenticator
+	enable  map[string]bool
+	disable map[string]bool
+}
+
+func (m *methodFilteredAuthenticator) Authenticate(ctx context.Context, method string) (*UserInfo, bool, error) {
+	if m.disable[method] {
+		return nil, false, nil
+	}
+	if m.enable != nil && !m.enable[method] {
+		return nil, false, nil
+	}
+	return m.Authenticator.Authenticate(ctx, method)
+}
+
+// authenticate runs the configured Authenticator chain in order, returning
+// the first UserInfo produced. It returns a gRPC Unauthenticated status if
+// every authenticator declines or one reports a hard failure.
+func authenticate(ctx context.Context, method string, chain []Authenticator) (*UserInfo, error) {
+	for _, a := range chain {
+		u, ok, err := a.Authenticate(ctx, method)
+		if err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "%s: %v", a.Name(), err)
+		}
+		if ok {
+			return u, nil
+		}
+	}
+	return nil, status.Error(codes.Unauthenticated, "unauthorized access to tiller")
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tiller
+
+import (
+	"crypto/tls"
+	"crypto/x509/pkix"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// MTLSAuthenticatorConfig configures how a client's identity is derived from
+// its peer certificate.
+type MTLSAuthenticatorConfig struct {
+	// UsernameField selects which part of the certificate subject becomes
+	// UserInfo.Name: "cn" (default) or "san" for the first DNS SAN.
+	UsernameField string `yaml:"usernameField"`
+	// GroupsOrganizationalUnit, when true, maps the certificate subject's
+	// Organizational Unit (OU) entries to UserInfo.Groups.
+	GroupsOrganizationalUnit bool `yaml:"groupsOrganizationalUnit"`
+}
+
+// mtlsAuthenticator derives a UserInfo from the verified peer certificate
+// presented over a mutual-TLS connection.
+type mtlsAuthenticator struct {
+	cfg MTLSAuthenticatorConfig
+}
+
+// NewMTLSAuthenticator builds an Authenticator that trusts the identity
+// asserted by a client's TLS certificate, as made available on the gRPC
+// peer.Peer of the connection.
+func NewMTLSAuthenticator(cfg MTLSAuthenticatorConfig) Authenticator {
+	if cfg.UsernameField == "" {
+		cfg.UsernameField = "cn"
+	}
+	return &mtlsAuthenticator{cfg: cfg}
+}
+
+func (m *mtlsAuthenticator) Name() string { return "mtls" }
+
+func (m *mtlsAuthenticator) Authenticate(ctx context.Context, method string) (*UserInfo, bool, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.AuthInfo == nil {
+		return nil, false, nil
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.PeerCertificates) == 0 {
+		return nil, false, nil
+	}
+
+	cert := tlsInfo.State.PeerCertificates[0]
+
+	name, err := m.usernameFromCert(cert.Subject, cert.DNSNames)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var groups []string
+	if m.cfg.GroupsOrganizationalUnit {
+		groups = cert.Subject.OrganizationalUnit
+	}
+
+	return &UserInfo{Name: name, Groups: groups}, true, nil
+}
+
+func (m *mtlsAuthenticator) usernameFromCert(subject pkix.Name, sans []string) (string, error) {
+	switch strings.ToLower(m.cfg.UsernameField) {
+	case "san":
+		if len(sans) == 0 {
+			return "", fmt.Errorf("mtls: client certificate has no DNS SANs")
+		}
+		return sans[0], nil
+	case "cn", "":
+		if subject.CommonName == "" {
+			return "", fmt.Errorf("mtls: client certificate has no CommonName")
+		}
+		return subject.CommonName, nil
+	default:
+		return "", fmt.Errorf("mtls: unknown usernameField %q", m.cfg.UsernameField)
+	}
+}
+
+// requireClientAuth returns the tls.ClientAuthType Tiller's gRPC server
+// credentials should use when an mtls authenticator is configured, so that
+// peer certificates are actually verified and populated on the connection.
+func requireClientAuth() tls.ClientAuthType {
+	return tls.RequireAndVerifyClientCert
+}
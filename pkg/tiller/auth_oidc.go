@@ -0,0 +1,162 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tiller
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
+
+	oidc "github.com/coreos/go-oidc"
+)
+
+// authTokenMetadataKey is the gRPC metadata key OIDC bearer tokens are read from.
+const authTokenMetadataKey = "x-helm-auth-token"
+
+// OIDCAuthenticatorConfig configures OIDC ID-token validation.
+type OIDCAuthenticatorConfig struct {
+	// IssuerURL is the OIDC issuer; its discovery document is used to locate
+	// and refresh the JWKS used to verify token signatures. Always required;
+	// with Offline set it is only used as the expected `iss` claim.
+	IssuerURL string `yaml:"issuerURL"`
+	// ClientID is the expected `aud` claim.
+	ClientID string `yaml:"clientID"`
+	// UsernameClaim is the claim mapped to UserInfo.Name. Defaults to "sub".
+	UsernameClaim string `yaml:"usernameClaim"`
+	// GroupsClaim is the claim mapped to UserInfo.Groups, expected to be a
+	// string array. Defaults to "groups".
+	GroupsClaim string `yaml:"groupsClaim"`
+
+	// Offline, when true, verifies tokens against the JWKS pinned at
+	// JWKSPath instead of the issuer's live discovery document, so
+	// authentication keeps working when Tiller can't reach IssuerURL. The
+	// pinned JWKS is loaded once, at startup; rotating signing keys
+	// requires restarting Tiller with a refreshed JWKSPath.
+	Offline bool `yaml:"offline"`
+	// JWKSPath is a JWKS JSON document on disk, required when Offline is
+	// set. IssuerURL is still used as the expected `iss` claim.
+	JWKSPath string `yaml:"jwksPath"`
+}
+
+// oidcAuthenticator validates bearer ID tokens carried in the
+// x-helm-auth-token metadata against an OIDC provider's JWKS, or, when
+// cfg.Offline is set, against a JWKS pinned to disk.
+type oidcAuthenticator struct {
+	cfg      OIDCAuthenticatorConfig
+	verifier *oidc.IDTokenVerifier
+}
+
+// NewOIDCAuthenticator builds an Authenticator that verifies OIDC ID tokens.
+// By default it verifies against the JWKS published by cfg.IssuerURL, with
+// key caching and refresh on a `kid` miss handled internally by
+// oidc.IDTokenVerifier's remote keyset, which Verify consults on every
+// call. When cfg.Offline is set, it instead verifies against the JWKS
+// pinned at cfg.JWKSPath and never calls out to cfg.IssuerURL.
+func NewOIDCAuthenticator(cfg OIDCAuthenticatorConfig) (Authenticator, error) {
+	if cfg.IssuerURL == "" {
+		return nil, fmt.Errorf("oidc: issuerURL is required")
+	}
+	if cfg.UsernameClaim == "" {
+		cfg.UsernameClaim = "sub"
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+
+	oidcCfg := &oidc.Config{
+		ClientID:          cfg.ClientID,
+		SkipClientIDCheck: cfg.ClientID == "",
+	}
+
+	var verifier *oidc.IDTokenVerifier
+	if cfg.Offline {
+		if cfg.JWKSPath == "" {
+			return nil, fmt.Errorf("oidc: jwksPath is required when offline is enabled")
+		}
+		keySet, err := newStaticKeySet(cfg.JWKSPath)
+		if err != nil {
+			return nil, fmt.Errorf("oidc: loading pinned jwks: %v", err)
+		}
+		verifier = oidc.NewVerifier(cfg.IssuerURL, keySet, oidcCfg)
+	} else {
+		provider, err := oidc.NewProvider(context.Background(), cfg.IssuerURL)
+		if err != nil {
+			return nil, fmt.Errorf("oidc: discovering issuer %s: %v", cfg.IssuerURL, err)
+		}
+		verifier = provider.Verifier(oidcCfg)
+	}
+
+	return &oidcAuthenticator{cfg: cfg, verifier: verifier}, nil
+}
+
+func (o *oidcAuthenticator) Name() string { return "oidc" }
+
+func (o *oidcAuthenticator) Authenticate(ctx context.Context, method string) (*UserInfo, bool, error) {
+	token, ok := bearerTokenFromContext(ctx)
+	if !ok {
+		return nil, false, nil
+	}
+
+	idToken, err := o.verifier.Verify(ctx, token)
+	if err != nil {
+		return nil, false, fmt.Errorf("verifying ID token: %v", err)
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, false, fmt.Errorf("decoding claims: %v", err)
+	}
+
+	name, _ := claims[o.cfg.UsernameClaim].(string)
+	if name == "" {
+		return nil, false, fmt.Errorf("claim %q missing or empty", o.cfg.UsernameClaim)
+	}
+
+	var groups []string
+	if raw, ok := claims[o.cfg.GroupsClaim].([]interface{}); ok {
+		for _, g := range raw {
+			if s, ok := g.(string); ok {
+				groups = append(groups, s)
+			}
+		}
+	}
+
+	return &UserInfo{Name: name, Groups: groups, Extra: stringsToExtra(claims)}, true, nil
+}
+
+func bearerTokenFromContext(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	v, ok := md[authTokenMetadataKey]
+	if !ok || len(v) == 0 || v[0] == "" {
+		return "", false
+	}
+	return v[0], true
+}
+
+func stringsToExtra(claims map[string]interface{}) map[string][]string {
+	extra := make(map[string][]string, len(claims))
+	for k, v := range claims {
+		if s, ok := v.(string); ok {
+			extra[k] = []string{s}
+		}
+	}
+	return extra
+}
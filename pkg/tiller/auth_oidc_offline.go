@@ -0,0 +1,78 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tiller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+// staticKeySet implements oidc.KeySet by verifying signatures against a JWKS
+// pinned to disk, making no network calls to the issuer. It backs
+// OIDCAuthenticatorConfig.Offline.
+type staticKeySet struct {
+	keys jose.JSONWebKeySet
+}
+
+// newStaticKeySet loads a JWKS document from jwksPath once, at
+// NewOIDCAuthenticator time.
+func newStaticKeySet(jwksPath string) (*staticKeySet, error) {
+	data, err := ioutil.ReadFile(jwksPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading jwks %s: %v", jwksPath, err)
+	}
+	var keys jose.JSONWebKeySet
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("parsing jwks %s: %v", jwksPath, err)
+	}
+	if len(keys.Keys) == 0 {
+		return nil, fmt.Errorf("jwks %s contains no keys", jwksPath)
+	}
+	return &staticKeySet{keys: keys}, nil
+}
+
+// VerifySignature implements oidc.KeySet. It matches jwt's `kid` against the
+// pinned JWKS, falling back to trying every pinned key when the token
+// carries no `kid`, and returns the verified payload.
+func (s *staticKeySet) VerifySignature(ctx context.Context, jwt string) ([]byte, error) {
+	sig, err := jose.ParseSigned(jwt)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: parsing jwt: %v", err)
+	}
+	if len(sig.Signatures) != 1 {
+		return nil, fmt.Errorf("oidc: expected 1 signature, got %d", len(sig.Signatures))
+	}
+
+	candidates := s.keys.Keys
+	if kid := sig.Signatures[0].Header.KeyID; kid != "" {
+		candidates = s.keys.Key(kid)
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("oidc: no pinned jwks key matches kid %q", sig.Signatures[0].Header.KeyID)
+	}
+
+	for _, key := range candidates {
+		if payload, err := sig.Verify(key); err == nil {
+			return payload, nil
+		}
+	}
+	return nil, fmt.Errorf("oidc: no pinned jwks key verified the token signature")
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tiller
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+func signForTest(t *testing.T, keyID string, key interface{}, alg jose.SignatureAlgorithm, payload []byte) string {
+	t.Helper()
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: jose.JSONWebKey{Key: key, KeyID: keyID, Algorithm: string(alg), Use: "sig"}}, nil)
+	if err != nil {
+		t.Fatalf("building signer: %v", err)
+	}
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		t.Fatalf("signing payload: %v", err)
+	}
+	token, err := jws.CompactSerialize()
+	if err != nil {
+		t.Fatalf("serializing jws: %v", err)
+	}
+	return token
+}
+
+func TestStaticKeySetVerifySignature(t *testing.T) {
+	const keyID = "test-key"
+	secret := []byte("01234567890123456789012345678901")
+	payload := []byte(`{"sub":"alice"}`)
+
+	token := signForTest(t, keyID, secret, jose.HS256, payload)
+
+	keySet := &staticKeySet{keys: jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: secret, KeyID: keyID, Algorithm: string(jose.HS256), Use: "sig"},
+	}}}
+
+	got, err := keySet.VerifySignature(context.Background(), token)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Fatalf("expected payload %s, got %s", payload, got)
+	}
+}
+
+func TestStaticKeySetVerifySignatureUnknownKeyID(t *testing.T) {
+	payload := []byte(`{"sub":"alice"}`)
+	token := signForTest(t, "signing-key", []byte("01234567890123456789012345678901"), jose.HS256, payload)
+
+	keySet := &staticKeySet{keys: jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: []byte("98765432109876543210987654321098"), KeyID: "other-key", Algorithm: string(jose.HS256), Use: "sig"},
+	}}}
+
+	if _, err := keySet.VerifySignature(context.Background(), token); err == nil {
+		t.Fatalf("expected an error when no pinned key matches the token's kid")
+	}
+}
+
+func TestStaticKeySetVerifySignatureWrongKey(t *testing.T) {
+	payload := []byte(`{"sub":"alice"}`)
+	token := signForTest(t, "test-key", []byte("01234567890123456789012345678901"), jose.HS256, payload)
+
+	// Same kid, different key material: the signature must not verify.
+	keySet := &staticKeySet{keys: jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: []byte("98765432109876543210987654321098"), KeyID: "test-key", Algorithm: string(jose.HS256), Use: "sig"},
+	}}}
+
+	if _, err := keySet.VerifySignature(context.Background(), token); err == nil {
+		t.Fatalf("expected an error when the pinned key doesn't match the signature")
+	}
+}
+
+func TestNewStaticKeySetRejectsEmptyJWKS(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "jwks.json")
+	if err := ioutil.WriteFile(path, []byte(`{"keys":[]}`), 0644); err != nil {
+		t.Fatalf("writing jwks: %v", err)
+	}
+	if _, err := newStaticKeySet(path); err == nil {
+		t.Fatalf("expected an error for a jwks file with no keys")
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tiller
+
+import (
+	"fmt"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeAuthenticator is a scripted Authenticator for exercising chain
+// short-circuiting without a real OIDC/mTLS/webhook backend.
+type fakeAuthenticator struct {
+	name string
+	u    *UserInfo
+	ok   bool
+	err  error
+}
+
+func (f *fakeAuthenticator) Name() string { return f.name }
+
+func (f *fakeAuthenticator) Authenticate(ctx context.Context, method string) (*UserInfo, bool, error) {
+	return f.u, f.ok, f.err
+}
+
+func TestAuthenticateChain(t *testing.T) {
+	alice := &UserInfo{Name: "alice"}
+	bob := &UserInfo{Name: "bob"}
+
+	tests := []struct {
+		name      string
+		chain     []Authenticator
+		wantUser  string
+		wantError bool
+	}{
+		{
+			name:      "empty chain is unauthenticated",
+			chain:     nil,
+			wantError: true,
+		},
+		{
+			name: "first authenticator wins",
+			chain: []Authenticator{
+				&fakeAuthenticator{name: "a", u: alice, ok: true},
+				&fakeAuthenticator{name: "b", u: bob, ok: true},
+			},
+			wantUser: "alice",
+		},
+		{
+			name: "declining authenticators are skipped",
+			chain: []Authenticator{
+				&fakeAuthenticator{name: "a", ok: false},
+				&fakeAuthenticator{name: "b", u: bob, ok: true},
+			},
+			wantUser: "bob",
+		},
+		{
+			name: "a hard error short-circuits the chain",
+			chain: []Authenticator{
+				&fakeAuthenticator{name: "a", err: fmt.Errorf("token expired")},
+				&fakeAuthenticator{name: "b", u: bob, ok: true},
+			},
+			wantError: true,
+		},
+		{
+			name: "every authenticator declining is unauthenticated",
+			chain: []Authenticator{
+				&fakeAuthenticator{name: "a", ok: false},
+				&fakeAuthenticator{name: "b", ok: false},
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := authenticate(context.Background(), "InstallRelease", tt.chain)
+			if tt.wantError {
+				if err == nil {
+					t.Fatalf("expected an error, got nil")
+				}
+				if status.Code(err) != codes.Unauthenticated {
+					t.Fatalf("expected codes.Unauthenticated, got %v", status.Code(err))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if u.Name != tt.wantUser {
+				t.Fatalf("expected user %q, got %q", tt.wantUser, u.Name)
+			}
+		})
+	}
+}
+
+func TestMethodFilteredAuthenticator(t *testing.T) {
+	inner := &fakeAuthenticator{name: "inner", u: &UserInfo{Name: "alice"}, ok: true}
+
+	tests := []struct {
+		name    string
+		enable  map[string]bool
+		disable map[string]bool
+		method  string
+		wantOK  bool
+	}{
+		{name: "no filters runs everywhere", method: "InstallRelease", wantOK: true},
+		{name: "enabled method runs", enable: map[string]bool{"InstallRelease": true}, method: "InstallRelease", wantOK: true},
+		{name: "method missing from enable set is skipped", enable: map[string]bool{"InstallRelease": true}, method: "GetReleaseStatus", wantOK: false},
+		{name: "disabled method is skipped even if enabled", enable: map[string]bool{"InstallRelease": true}, disable: map[string]bool{"InstallRelease": true}, method: "InstallRelease", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &methodFilteredAuthenticator{Authenticator: inner, enable: tt.enable, disable: tt.disable}
+			_, ok, err := m.Authenticate(context.Background(), tt.method)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ok != tt.wantOK {
+				t.Fatalf("expected ok=%v, got %v", tt.wantOK, ok)
+			}
+		})
+	}
+}
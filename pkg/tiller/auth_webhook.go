@@ -0,0 +1,174 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tiller
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+)
+
+// WebhookAuthenticatorConfig configures a Kubernetes TokenReview webhook
+// used to validate bearer tokens carried in the x-helm-auth-token metadata.
+type WebhookAuthenticatorConfig struct {
+	// URL is the TokenReview webhook endpoint, e.g. the apiserver's
+	// /apis/authentication.k8s.io/v1/tokenreviews.
+	URL string `yaml:"url"`
+	// CacheTTL controls how long a successful review is cached, keyed by a
+	// hash of the presented token, to avoid a round-trip per RPC.
+	CacheTTL time.Duration `yaml:"cacheTTL"`
+	// Timeout bounds how long a single webhook call may take.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type webhookAuthenticator struct {
+	cfg    WebhookAuthenticatorConfig
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]cachedReview
+}
+
+type cachedReview struct {
+	info      *UserInfo
+	expiresAt time.Time
+}
+
+// NewWebhookAuthenticator builds an Authenticator that delegates token
+// validation to a Kubernetes TokenReview webhook, caching successful
+// reviews for cfg.CacheTTL.
+func NewWebhookAuthenticator(cfg WebhookAuthenticatorConfig) (Authenticator, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("webhook: url is required")
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.CacheTTL == 0 {
+		cfg.CacheTTL = 30 * time.Second
+	}
+	return &webhookAuthenticator{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		cache:  make(map[string]cachedReview),
+	}, nil
+}
+
+func (w *webhookAuthenticator) Name() string { return "webhook" }
+
+func (w *webhookAuthenticator) Authenticate(ctx context.Context, method string) (*UserInfo, bool, error) {
+	token, ok := bearerTokenFromContext(ctx)
+	if !ok {
+		return nil, false, nil
+	}
+
+	key := tokenCacheKey(token)
+	if u, ok := w.cached(key); ok {
+		return u, true, nil
+	}
+
+	u, err := w.review(ctx, token)
+	if err != nil {
+		return nil, false, err
+	}
+	if u == nil {
+		return nil, false, nil
+	}
+
+	w.store(key, u)
+	return u, true, nil
+}
+
+func (w *webhookAuthenticator) cached(key string) (*UserInfo, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	c, ok := w.cache[key]
+	if !ok || time.Now().After(c.expiresAt) {
+		return nil, false
+	}
+	return c.info, true
+}
+
+func (w *webhookAuthenticator) store(key string, u *UserInfo) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.cache[key] = cachedReview{info: u, expiresAt: time.Now().Add(w.cfg.CacheTTL)}
+}
+
+func (w *webhookAuthenticator) review(ctx context.Context, token string) (*UserInfo, error) {
+	reqBody := authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling TokenReview: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("building TokenReview request: %v", err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("calling TokenReview webhook: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("TokenReview webhook returned status %d", resp.StatusCode)
+	}
+
+	var review authenticationv1.TokenReview
+	if err := json.NewDecoder(resp.Body).Decode(&review); err != nil {
+		return nil, fmt.Errorf("decoding TokenReview response: %v", err)
+	}
+
+	if review.Status.Error != "" {
+		return nil, fmt.Errorf("TokenReview webhook error: %s", review.Status.Error)
+	}
+	if !review.Status.Authenticated {
+		return nil, nil
+	}
+
+	extra := make(map[string][]string, len(review.Status.User.Extra))
+	for k, v := range review.Status.User.Extra {
+		extra[k] = []string(v)
+	}
+
+	return &UserInfo{
+		Name:   review.Status.User.Username,
+		Groups: review.Status.User.Groups,
+		Extra:  extra,
+	}, nil
+}
+
+func tokenCacheKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,336 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tiller
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"regexp"
+	"sync"
+	"syscall"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v2"
+
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// Request describes the action an authenticated caller is attempting, as
+// derived from the RPC method and, where available, the release request
+// body.
+type Request struct {
+	// Verb is the action being performed, e.g. "install", "upgrade",
+	// "delete", "list", "get", "rollback". Derived from splitMethod.
+	Verb string
+	// Namespace the release lives, or is being installed, in. May be empty
+	// for namespace-agnostic calls such as ListReleases.
+	Namespace string
+	// Release is the release name the call targets, if any.
+	Release string
+	// ChartName and ChartVersion identify the chart being installed or
+	// upgraded, if the request carries one (e.g. InstallRelease,
+	// UpdateRelease). Empty for chart-agnostic calls.
+	ChartName    string
+	ChartVersion string
+}
+
+// ChartRef is the "name-version" identifier of the chart this Request
+// carries, matching the naming convention of a packaged chart archive.
+// Empty when the request has no chart (e.g. ListReleases, GetReleaseStatus).
+func (r Request) ChartRef() string {
+	if r.ChartName == "" {
+		return ""
+	}
+	if r.ChartVersion == "" {
+		return r.ChartName
+	}
+	return r.ChartName + "-" + r.ChartVersion
+}
+
+// Decision is the outcome of an authorization check.
+type Decision struct {
+	Allowed bool
+	// Reason is a human-readable explanation, always set on denial and
+	// returned to the caller as part of the gRPC PermissionDenied status.
+	Reason string
+}
+
+// Authorizer decides whether an authenticated UserInfo may perform Request.
+type Authorizer interface {
+	Authorize(ctx context.Context, u *UserInfo, req Request) (Decision, error)
+}
+
+// methodVerbs maps gRPC method names to the RBAC verb they require.
+var methodVerbs = map[string]string{
+	"InstallRelease":    "install",
+	"UpdateRelease":     "upgrade",
+	"UninstallRelease":  "delete",
+	"ListReleases":      "list",
+	"GetReleaseStatus":  "get",
+	"GetReleaseContent": "get",
+	"GetHistory":        "get",
+	"RollbackRelease":   "rollback",
+}
+
+func verbForMethod(method string) string {
+	if v, ok := methodVerbs[method]; ok {
+		return v
+	}
+	return method
+}
+
+// AuthzConfig is the on-disk, hot-reloadable RBAC policy file.
+type AuthzConfig struct {
+	Policies []Policy `yaml:"policies"`
+}
+
+// Policy grants the listed subjects the listed verbs over releases whose
+// namespace and name match Namespaces/ReleasePattern.
+type Policy struct {
+	// Users and Groups list the subjects this policy applies to. A policy
+	// with no Users and no Groups applies to every caller, including one
+	// with no UserInfo.
+	Users  []string `yaml:"users,omitempty"`
+	Groups []string `yaml:"groups,omitempty"`
+
+	Verbs []string `yaml:"verbs"`
+
+	// Namespaces this policy applies to. Empty means all namespaces.
+	Namespaces []string `yaml:"namespaces,omitempty"`
+	// ReleasePattern is a regexp matched against the release name. Empty
+	// matches every release.
+	ReleasePattern string `yaml:"releasePattern,omitempty"`
+
+	compiledPattern *regexp.Regexp
+}
+
+func (p *Policy) compile() error {
+	if p.ReleasePattern == "" {
+		return nil
+	}
+	re, err := regexp.Compile(p.ReleasePattern)
+	if err != nil {
+		return fmt.Errorf("invalid releasePattern %q: %v", p.ReleasePattern, err)
+	}
+	p.compiledPattern = re
+	return nil
+}
+
+// RBACAuthorizer evaluates Policy entries loaded from a YAML file, reloading
+// them whenever the process receives SIGHUP.
+type RBACAuthorizer struct {
+	path string
+
+	mu       sync.RWMutex
+	policies []Policy
+}
+
+// NewRBACAuthorizer loads an AuthzConfig from path and installs a SIGHUP
+// handler that hot-reloads it for the lifetime of the process.
+func NewRBACAuthorizer(path string) (*RBACAuthorizer, error) {
+	a := &RBACAuthorizer{path: path}
+	if err := a.reload(); err != nil {
+		return nil, err
+	}
+	a.watchSIGHUP()
+	return a, nil
+}
+
+func (a *RBACAuthorizer) reload() error {
+	cfg, err := loadAuthzConfig(a.path)
+	if err != nil {
+		return err
+	}
+	for i := range cfg.Policies {
+		if err := cfg.Policies[i].compile(); err != nil {
+			return fmt.Errorf("policy %d: %v", i, err)
+		}
+	}
+	a.mu.Lock()
+	a.policies = cfg.Policies
+	a.mu.Unlock()
+	return nil
+}
+
+func (a *RBACAuthorizer) watchSIGHUP() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	go func() {
+		for range ch {
+			if err := a.reload(); err != nil {
+				fmt.Fprintf(os.Stderr, "rbac: failed to reload %s: %v\n", a.path, err)
+			}
+		}
+	}()
+}
+
+func loadAuthzConfig(path string) (*AuthzConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading authz config: %v", err)
+	}
+	cfg := &AuthzConfig{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parsing authz config %s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// Authorize implements Authorizer.
+func (a *RBACAuthorizer) Authorize(ctx context.Context, u *UserInfo, req Request) (Decision, error) {
+	a.mu.RLock()
+	policies := a.policies
+	a.mu.RUnlock()
+
+	for _, p := range policies {
+		if p.matches(u, req) {
+			return Decision{Allowed: true}, nil
+		}
+	}
+	return Decision{
+		Allowed: false,
+		Reason:  fmt.Sprintf("no policy grants %s %q permission for verb %q in namespace %q", describeSubject(u), req.Release, req.Verb, req.Namespace),
+	}, nil
+}
+
+func describeSubject(u *UserInfo) string {
+	if u == nil || u.Name == "" {
+		return "anonymous"
+	}
+	return u.Name
+}
+
+func (p *Policy) matches(u *UserInfo, req Request) bool {
+	if !p.hasVerb(req.Verb) {
+		return false
+	}
+	if !p.subjectMatches(u) {
+		return false
+	}
+	if !p.namespaceMatches(req.Namespace) {
+		return false
+	}
+	return p.releaseMatches(req.Release)
+}
+
+func (p *Policy) hasVerb(verb string) bool {
+	for _, v := range p.Verbs {
+		if v == "*" || v == verb {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Policy) subjectMatches(u *UserInfo) bool {
+	if len(p.Users) == 0 && len(p.Groups) == 0 {
+		return true
+	}
+	if u == nil {
+		return false
+	}
+	for _, name := range p.Users {
+		if name == u.Name {
+			return true
+		}
+	}
+	for _, g := range p.Groups {
+		for _, ug := range u.Groups {
+			if g == ug {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *Policy) namespaceMatches(ns string) bool {
+	if len(p.Namespaces) == 0 {
+		return true
+	}
+	for _, n := range p.Namespaces {
+		if n == ns {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Policy) releaseMatches(release string) bool {
+	if p.compiledPattern == nil {
+		return true
+	}
+	return p.compiledPattern.MatchString(release)
+}
+
+// named getters optionally implemented by generated release request/response
+// messages (InstallReleaseRequest, UpdateReleaseRequest, UninstallReleaseRequest,
+// GetReleaseStatusRequest, RollbackReleaseRequest, ...).
+type namedMessage interface {
+	GetName() string
+}
+
+type namespacedMessage interface {
+	GetNamespace() string
+}
+
+// chartedMessage is implemented by requests that carry a chart to install or
+// upgrade (InstallReleaseRequest, UpdateReleaseRequest).
+type chartedMessage interface {
+	GetChart() *chart.Chart
+}
+
+// requestFromMessage builds the Request an Authorizer evaluates from the RPC
+// method and, where the message implements GetName/GetNamespace/GetChart,
+// its body.
+func requestFromMessage(method string, msg interface{}) Request {
+	req := Request{Verb: verbForMethod(method)}
+	if nm, ok := msg.(namedMessage); ok {
+		req.Release = nm.GetName()
+	}
+	if ns, ok := msg.(namespacedMessage); ok {
+		req.Namespace = ns.GetNamespace()
+	}
+	if cm, ok := msg.(chartedMessage); ok {
+		if c := cm.GetChart(); c != nil && c.Metadata != nil {
+			req.ChartName = c.Metadata.Name
+			req.ChartVersion = c.Metadata.Version
+		}
+	}
+	return req
+}
+
+// authorize is invoked from the unary/stream interceptors once a UserInfo is
+// available. It returns a gRPC PermissionDenied status carrying Decision.Reason
+// when the Authorizer denies the request.
+func (f *ServerOptsFactory) authorize(ctx context.Context, u *UserInfo, req Request) error {
+	if f.Authorizer == nil {
+		return nil
+	}
+	d, err := f.Authorizer.Authorize(ctx, u, req)
+	if err != nil {
+		return status.Errorf(codes.Internal, "authorization check failed: %v", err)
+	}
+	if !d.Allowed {
+		return status.Error(codes.PermissionDenied, d.Reason)
+	}
+	return nil
+}
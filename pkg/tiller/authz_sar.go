@@ -0,0 +1,85 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tiller
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// sarAuthorizer delegates authorization decisions to the Kubernetes
+// apiserver via SubjectAccessReview, using the authenticated user/groups
+// from the request's UserInfo as the review's subject.
+type sarAuthorizer struct {
+	client kubernetes.Interface
+	// resource is the apiserver resource used in every review, e.g.
+	// "releases.tiller.helm.sh". Namespace and verb come from the Request.
+	resource string
+}
+
+// NewSubjectAccessReviewAuthorizer builds an Authorizer that asks the
+// Kubernetes apiserver whether the authenticated subject may perform each
+// Request, via a SubjectAccessReview.
+func NewSubjectAccessReviewAuthorizer(client kubernetes.Interface, resource string) Authorizer {
+	if resource == "" {
+		resource = "releases.tiller.helm.sh"
+	}
+	return &sarAuthorizer{client: client, resource: resource}
+}
+
+func (s *sarAuthorizer) Authorize(ctx context.Context, u *UserInfo, req Request) (Decision, error) {
+	if u == nil {
+		return Decision{Allowed: false, Reason: "no authenticated user"}, nil
+	}
+
+	extra := make(map[string]authorizationv1.ExtraValue, len(u.Extra))
+	for k, v := range u.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+
+	sar := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   u.Name,
+			Groups: u.Groups,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: req.Namespace,
+				Verb:      req.Verb,
+				Resource:  s.resource,
+				Name:      req.Release,
+			},
+		},
+	}
+
+	result, err := s.client.AuthorizationV1().SubjectAccessReviews().Create(sar)
+	if err != nil {
+		return Decision{}, fmt.Errorf("SubjectAccessReview: %v", err)
+	}
+
+	if !result.Status.Allowed {
+		reason := result.Status.Reason
+		if reason == "" {
+			reason = fmt.Sprintf("apiserver denied verb %q on %s %q in namespace %q", req.Verb, s.resource, req.Release, req.Namespace)
+		}
+		return Decision{Allowed: false, Reason: reason}, nil
+	}
+
+	return Decision{Allowed: true}, nil
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tiller
+
+import "testing"
+
+func mustCompile(t *testing.T, p Policy) Policy {
+	t.Helper()
+	if err := p.compile(); err != nil {
+		t.Fatalf("compiling policy: %v", err)
+	}
+	return p
+}
+
+func TestPolicyMatches(t *testing.T) {
+	alice := &UserInfo{Name: "alice", Groups: []string{"operators"}}
+
+	tests := []struct {
+		name   string
+		policy Policy
+		u      *UserInfo
+		req    Request
+		want   bool
+	}{
+		{
+			name:   "wildcard verb, no subject restriction",
+			policy: Policy{Verbs: []string{"*"}},
+			u:      alice,
+			req:    Request{Verb: "install"},
+			want:   true,
+		},
+		{
+			name:   "verb mismatch is denied",
+			policy: Policy{Verbs: []string{"get"}},
+			u:      alice,
+			req:    Request{Verb: "install"},
+			want:   false,
+		},
+		{
+			name:   "named user matches",
+			policy: Policy{Verbs: []string{"install"}, Users: []string{"alice"}},
+			u:      alice,
+			req:    Request{Verb: "install"},
+			want:   true,
+		},
+		{
+			name:   "named user mismatch is denied",
+			policy: Policy{Verbs: []string{"install"}, Users: []string{"bob"}},
+			u:      alice,
+			req:    Request{Verb: "install"},
+			want:   false,
+		},
+		{
+			name:   "group matches",
+			policy: Policy{Verbs: []string{"install"}, Groups: []string{"operators"}},
+			u:      alice,
+			req:    Request{Verb: "install"},
+			want:   true,
+		},
+		{
+			name:   "subject restriction denies anonymous",
+			policy: Policy{Verbs: []string{"install"}, Users: []string{"alice"}},
+			u:      nil,
+			req:    Request{Verb: "install"},
+			want:   false,
+		},
+		{
+			name:   "namespace restriction matches",
+			policy: Policy{Verbs: []string{"install"}, Namespaces: []string{"staging"}},
+			u:      alice,
+			req:    Request{Verb: "install", Namespace: "staging"},
+			want:   true,
+		},
+		{
+			name:   "namespace restriction denies other namespaces",
+			policy: Policy{Verbs: []string{"install"}, Namespaces: []string{"staging"}},
+			u:      alice,
+			req:    Request{Verb: "install", Namespace: "prod"},
+			want:   false,
+		},
+		{
+			name:   "release pattern matches",
+			policy: mustCompile(t, Policy{Verbs: []string{"install"}, ReleasePattern: `^canary-`}),
+			u:      alice,
+			req:    Request{Verb: "install", Release: "canary-1"},
+			want:   true,
+		},
+		{
+			name:   "release pattern denies non-matching release",
+			policy: mustCompile(t, Policy{Verbs: []string{"install"}, ReleasePattern: `^canary-`}),
+			u:      alice,
+			req:    Request{Verb: "install", Release: "prod-1"},
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.matches(tt.u, tt.req); got != tt.want {
+				t.Fatalf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestChartRef(t *testing.T) {
+	tests := []struct {
+		name string
+		req  Request
+		want string
+	}{
+		{name: "no chart", req: Request{}, want: ""},
+		{name: "name only", req: Request{ChartName: "nginx"}, want: "nginx"},
+		{name: "name and version", req: Request{ChartName: "nginx", ChartVersion: "1.2.3"}, want: "nginx-1.2.3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.req.ChartRef(); got != tt.want {
+				t.Fatalf("ChartRef() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
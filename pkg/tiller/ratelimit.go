@@ -0,0 +1,198 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tiller
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// mutatingMethods are serialized per-release by the concurrency limiter so
+// two callers can never race to install/upgrade/rollback/uninstall the same
+// release concurrently.
+var mutatingMethods = map[string]bool{
+	"InstallRelease":   true,
+	"UpdateRelease":    true,
+	"RollbackRelease":  true,
+	"UninstallRelease": true,
+}
+
+// RateLimitConfig configures the token-bucket limiter keyed by
+// (authenticatedUser, method).
+type RateLimitConfig struct {
+	// Enabled turns rate limiting on.
+	Enabled bool `yaml:"enabled"`
+	// RatePerSecond is the default sustained rate, in requests/sec, for any
+	// (user, method) pair not named in PerMethod.
+	RatePerSecond float64 `yaml:"ratePerSecond"`
+	// Burst is the default token bucket size.
+	Burst int `yaml:"burst"`
+	// PerMethod overrides RatePerSecond/Burst for specific methods, e.g. a
+	// tighter limit on InstallRelease than on GetReleaseStatus.
+	PerMethod map[string]MethodRateLimit `yaml:"perMethod,omitempty"`
+}
+
+// MethodRateLimit is a per-method override of the default rate limit.
+type MethodRateLimit struct {
+	RatePerSecond float64 `yaml:"ratePerSecond"`
+	Burst         int     `yaml:"burst"`
+}
+
+func (c RateLimitConfig) limitFor(method string) (float64, int) {
+	if m, ok := c.PerMethod[method]; ok {
+		return m.RatePerSecond, m.Burst
+	}
+	return c.RatePerSecond, c.Burst
+}
+
+// rateLimiter grants a token-bucket limiter per (user, method) pair,
+// creating limiters on demand and never shrinking the map, matching the
+// lifetime of the process.
+type rateLimiter struct {
+	cfg RateLimitConfig
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+func newRateLimiter(cfg RateLimitConfig) *rateLimiter {
+	return &rateLimiter{cfg: cfg, limiters: make(map[string]*rate.Limiter)}
+}
+
+func (r *rateLimiter) allow(user, method string) bool {
+	key := user + "/" + method
+	r.mu.Lock()
+	l, ok := r.limiters[key]
+	if !ok {
+		rps, burst := r.cfg.limitFor(method)
+		if rps <= 0 {
+			rps = 5
+		}
+		if burst <= 0 {
+			burst = int(rps)
+			if burst < 1 {
+				burst = 1
+			}
+		}
+		l = rate.NewLimiter(rate.Limit(rps), burst)
+		r.limiters[key] = l
+	}
+	r.mu.Unlock()
+	return l.Allow()
+}
+
+// releaseLocks serializes mutating RPCs (install/update/rollback/uninstall)
+// against the same release name, so Tiller never applies two conflicting
+// updates to a release concurrently.
+type releaseLocks struct {
+	mu    sync.Mutex
+	locks map[string]*releaseLock
+}
+
+// releaseLock is a per-release mutex plus a count of callers currently
+// holding or waiting on it, so releaseLocks can evict the entry once it's
+// idle instead of growing forever as releases are installed and uninstalled
+// over the life of a long-running Tiller.
+type releaseLock struct {
+	mu       sync.Mutex
+	refCount int
+}
+
+func newReleaseLocks() *releaseLocks {
+	return &releaseLocks{locks: make(map[string]*releaseLock)}
+}
+
+// lock acquires the mutex for release, creating it on demand, and returns an
+// unlock func that releases it and, once no other caller is holding or
+// waiting on this release, evicts it from the map.
+func (r *releaseLocks) lock(release string) func() {
+	r.mu.Lock()
+	l, ok := r.locks[release]
+	if !ok {
+		l = &releaseLock{}
+		r.locks[release] = l
+	}
+	l.refCount++
+	r.mu.Unlock()
+
+	l.mu.Lock()
+
+	return func() {
+		l.mu.Unlock()
+		r.mu.Lock()
+		l.refCount--
+		if l.refCount == 0 {
+			delete(r.locks, release)
+		}
+		r.mu.Unlock()
+	}
+}
+
+// throttled returns the gRPC ResourceExhausted status for a throttled call.
+// The Retry-After hint is not part of this status; rateLimitAndLock sends it
+// separately as trailing metadata via setRetryAfterTrailer.
+func throttled(user, method string, retryAfter time.Duration) error {
+	st := status.New(codes.ResourceExhausted, fmt.Sprintf("rate limit exceeded for user %q calling %s", user, method))
+	return st.Err()
+}
+
+func setRetryAfterTrailer(ctx context.Context, retryAfter time.Duration) {
+	grpc.SetTrailer(ctx, metadata.Pairs("retry-after", retryAfter.String()))
+}
+
+// rateLimitAndLock is invoked from the unary/stream interceptors, after
+// authentication, to apply both the per-user token bucket and, for mutating
+// methods, the per-release mutex. It returns a function that must be called
+// to release the per-release lock, or a no-op function if none was taken;
+// unlock is nil only when err is non-nil.
+func (f *ServerOptsFactory) rateLimitAndLock(ctx context.Context, user, method, release string) (unlock func(), err error) {
+	if f.RateLimit.Enabled {
+		if !f.rateLimiter().allow(user, method) {
+			retryAfter := time.Second
+			setRetryAfterTrailer(ctx, retryAfter)
+			return nil, throttled(user, method, retryAfter)
+		}
+	}
+
+	if mutatingMethods[method] && release != "" {
+		return f.releaseLocksSet().lock(release), nil
+	}
+
+	return func() {}, nil
+}
+
+func (f *ServerOptsFactory) rateLimiter() *rateLimiter {
+	f.rateLimiterOnce.Do(func() {
+		f.rateLimiterInstance = newRateLimiter(f.RateLimit)
+	})
+	return f.rateLimiterInstance
+}
+
+func (f *ServerOptsFactory) releaseLocksSet() *releaseLocks {
+	f.releaseLocksOnce.Do(func() {
+		f.releaseLocksInstance = newReleaseLocks()
+	})
+	return f.releaseLocksInstance
+}
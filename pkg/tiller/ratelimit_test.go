@@ -0,0 +1,98 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tiller
+
+import "testing"
+
+func TestRateLimiterAllow(t *testing.T) {
+	rl := newRateLimiter(RateLimitConfig{
+		RatePerSecond: 1,
+		Burst:         2,
+		PerMethod: map[string]MethodRateLimit{
+			"InstallRelease": {RatePerSecond: 1, Burst: 1},
+		},
+	})
+
+	// Default bucket: burst of 2 allowed back to back, the 3rd is throttled.
+	if !rl.allow("alice", "GetReleaseStatus") {
+		t.Fatalf("expected 1st call within burst to be allowed")
+	}
+	if !rl.allow("alice", "GetReleaseStatus") {
+		t.Fatalf("expected 2nd call within burst to be allowed")
+	}
+	if rl.allow("alice", "GetReleaseStatus") {
+		t.Fatalf("expected 3rd call to exceed the burst and be throttled")
+	}
+
+	// A per-method override with burst 1 only allows a single call.
+	if !rl.allow("alice", "InstallRelease") {
+		t.Fatalf("expected 1st InstallRelease call to be allowed")
+	}
+	if rl.allow("alice", "InstallRelease") {
+		t.Fatalf("expected 2nd InstallRelease call to exceed its burst of 1")
+	}
+
+	// Buckets are keyed per (user, method): bob gets his own limiter.
+	if !rl.allow("bob", "GetReleaseStatus") {
+		t.Fatalf("expected a different user's bucket to be independent")
+	}
+}
+
+func TestReleaseLocksEvictsIdleEntries(t *testing.T) {
+	locks := newReleaseLocks()
+
+	unlock := locks.lock("my-release")
+	if len(locks.locks) != 1 {
+		t.Fatalf("expected 1 tracked release lock while held, got %d", len(locks.locks))
+	}
+	unlock()
+	if len(locks.locks) != 0 {
+		t.Fatalf("expected the entry to be evicted once idle, got %d remaining", len(locks.locks))
+	}
+
+	// A second, unrelated release doesn't resurrect the evicted entry.
+	unlock2 := locks.lock("other-release")
+	if len(locks.locks) != 1 {
+		t.Fatalf("expected 1 tracked release lock, got %d", len(locks.locks))
+	}
+	unlock2()
+	if len(locks.locks) != 0 {
+		t.Fatalf("expected the map to be empty again, got %d remaining", len(locks.locks))
+	}
+}
+
+func TestReleaseLocksSerializesSameRelease(t *testing.T) {
+	locks := newReleaseLocks()
+
+	unlock := locks.lock("my-release")
+
+	done := make(chan struct{})
+	go func() {
+		// Blocks until the first lock is released.
+		locks.lock("my-release")()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatalf("expected the second lock() call to block while the first is held")
+	default:
+	}
+
+	unlock()
+	<-done
+}
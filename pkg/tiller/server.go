@@ -18,10 +18,12 @@ package tiller
 
 import (
 	"fmt"
-	"log"
 	"strings"
+	"sync"
+	"time"
 
 	goprom "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
@@ -35,11 +37,42 @@ var maxMsgSize = 1024 * 1024 * 20
 
 // ServerOptsFactory creates a set of `grpc.ServerOption` to add validation, authn and authz to Tiller
 type ServerOptsFactory struct {
+	// AuthProxyEnabled enables the legacy authentication mode, which trusts
+	// the x-forwarded-user/x-forwarded-groups headers set by a fronting
+	// reverse proxy. Prefer Authenticators for new deployments.
 	AuthProxyEnabled bool
+
+	// Authenticators, when non-empty, are tried in order for every RPC
+	// instead of the legacy x-forwarded-user header check. Build this list
+	// with NewAuthenticatorChain.
+	Authenticators []Authenticator
+
+	// Authorizer, when set, is consulted after authentication on every RPC.
+	// Build one with NewRBACAuthorizer or NewSubjectAccessReviewAuthorizer.
+	Authorizer Authorizer
+
+	// Tracing configures OpenTelemetry spans for incoming RPCs.
+	Tracing TracingConfig
+
+	// RateLimit configures the per-user, per-method token bucket. Mutating
+	// RPCs are also serialized per release, whether or not RateLimit is
+	// enabled.
+	RateLimit RateLimitConfig
+
+	// Audit configures structured, per-RPC audit logging.
+	Audit AuditConfig
+
+	tracerProviderOnce sync.Once
+	tracerProvider     trace.TracerProvider
+
+	rateLimiterOnce      sync.Once
+	rateLimiterInstance  *rateLimiter
+	releaseLocksOnce     sync.Once
+	releaseLocksInstance *releaseLocks
 }
 
 // DefaultServerOpts returns the set of default grpc ServerOption's that Tiller requires.
-func (f ServerOptsFactory) DefaultServerOpts() []grpc.ServerOption {
+func (f *ServerOptsFactory) DefaultServerOpts() []grpc.ServerOption {
 	return []grpc.ServerOption{
 		grpc.MaxMsgSize(maxMsgSize),
 		grpc.UnaryInterceptor(f.newUnaryInterceptor()),
@@ -54,32 +87,157 @@ func NewServer(f *ServerOptsFactory, opts ...grpc.ServerOption) *grpc.Server {
 
 func (f *ServerOptsFactory) newUnaryInterceptor() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
-		if err := checkClientVersion(ctx); err != nil {
+		start := time.Now()
+		_, method := splitMethod(info.FullMethod)
+		release := requestFromMessage(method, req)
+		auditCtx := ctx
+
+		defer func() {
+			f.auditUnary(auditCtx, method, release, start, err)
+		}()
+
+		if err = checkClientVersion(ctx); err != nil {
 			// whitelist GetVersion() from the version check
 			if _, m := splitMethod(info.FullMethod); m != "GetVersion" {
-				log.Println(err)
 				return nil, err
 			}
+			err = nil
+		}
+
+		ctx, span := f.startSpan(ctx, method)
+		defer span.End()
+
+		newCtx, err2 := f.authenticate(ctx, method)
+		if err2 != nil {
+			err = err2
+			span.RecordError(err)
+			return nil, err
 		}
-		if err := f.optionallyCheckAuthenticatedUser(ctx); err != nil {
+		auditCtx = newCtx
+
+		u, _ := UserInfoFromContext(newCtx)
+		annotateRelease(newCtx, release.Release, release.Namespace, release.ChartName, release.ChartVersion)
+		if err = f.authorize(newCtx, u, release); err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+
+		unlock, err3 := f.rateLimitAndLock(newCtx, describeSubject(u), method, release.Release)
+		if err3 != nil {
+			err = err3
+			span.RecordError(err)
 			return nil, err
 		}
-		return goprom.UnaryServerInterceptor(ctx, req, info, handler)
+		defer unlock()
+
+		resp, err = goprom.UnaryServerInterceptor(newCtx, req, info, handler)
+		if err != nil {
+			span.RecordError(err)
+		}
+		return resp, err
 	}
 }
 
 func (f *ServerOptsFactory) newStreamInterceptor() grpc.StreamServerInterceptor {
-	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		start := time.Now()
 		ctx := ss.Context()
-		if err := checkClientVersion(ctx); err != nil {
-			log.Println(err)
+		_, method := splitMethod(info.FullMethod)
+		streamReq := Request{Verb: verbForMethod(method)}
+		auditCtx := ctx
+		var unlock func()
+
+		defer func() {
+			f.auditUnary(auditCtx, method, streamReq, start, err)
+			if unlock != nil {
+				unlock()
+			}
+		}()
+
+		if err = checkClientVersion(ctx); err != nil {
 			return err
 		}
-		if err := f.optionallyCheckAuthenticatedUser(ctx); err != nil {
+
+		ctx, span := f.startSpan(ctx, method)
+		defer span.End()
+
+		newCtx, err2 := f.authenticate(ctx, method)
+		if err2 != nil {
+			err = err2
+			span.RecordError(err)
 			return err
 		}
-		return goprom.StreamServerInterceptor(srv, ss, info, handler)
+		auditCtx = newCtx
+
+		u, _ := UserInfoFromContext(newCtx)
+
+		// Tiller's streaming RPCs (e.g. RunReleaseTest) take a single request
+		// message before streaming responses back, so authorization and rate
+		// limiting can only run once that first message has been received.
+		// authorizeFirstMsg runs from the wrapped stream's RecvMsg, before the
+		// handler ever observes the message, so a denial is indistinguishable
+		// from the handler never having been allowed to start.
+		authorizeFirstMsg := func(msg interface{}) error {
+			streamReq = requestFromMessage(method, msg)
+			auditCtx = newCtx
+			annotateRelease(newCtx, streamReq.Release, streamReq.Namespace, streamReq.ChartName, streamReq.ChartVersion)
+
+			if err := f.authorize(newCtx, u, streamReq); err != nil {
+				span.RecordError(err)
+				return err
+			}
+
+			var lockErr error
+			unlock, lockErr = f.rateLimitAndLock(newCtx, describeSubject(u), method, streamReq.Release)
+			if lockErr != nil {
+				span.RecordError(lockErr)
+				return lockErr
+			}
+			return nil
+		}
+
+		wrapped := &authenticatedServerStream{ServerStream: ss, ctx: newCtx, authorizeFirstMsg: authorizeFirstMsg}
+
+		err = goprom.StreamServerInterceptor(srv, wrapped, info, handler)
+		if err != nil {
+			span.RecordError(err)
+		}
+		return err
+	}
+}
+
+// authenticatedServerStream wraps a grpc.ServerStream to override Context()
+// with the ctx produced by the authentication interceptor, so that stream
+// handlers can retrieve UserInfoFromContext. It also runs authorizeFirstMsg,
+// if set, against the first message the handler receives, since that's the
+// earliest point a streaming RPC's request body (and therefore its release,
+// namespace, and chart) is available.
+type authenticatedServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+
+	authorizeFirstMsg func(msg interface{}) error
+	authorizeOnce     sync.Once
+	authorizeErr      error
+}
+
+func (a *authenticatedServerStream) Context() context.Context {
+	return a.ctx
+}
+
+func (a *authenticatedServerStream) RecvMsg(msg interface{}) error {
+	if err := a.ServerStream.RecvMsg(msg); err != nil {
+		return err
+	}
+	if a.authorizeFirstMsg != nil {
+		a.authorizeOnce.Do(func() {
+			a.authorizeErr = a.authorizeFirstMsg(msg)
+		})
+		if a.authorizeErr != nil {
+			return a.authorizeErr
+		}
 	}
+	return nil
 }
 
 func splitMethod(fullMethod string) (string, string) {
@@ -110,7 +268,6 @@ func authenticatedUserFromContext(ctx context.Context) (string, []string) {
 	user := ""
 	groups := []string{}
 	if md, ok := metadata.FromIncomingContext(ctx); ok {
-		log.Printf("Request Metadata: %v", md)
 		if v, ok := md["x-forwarded-user"]; ok && len(v) > 0 {
 			user = v[0]
 		}
@@ -121,18 +278,24 @@ func authenticatedUserFromContext(ctx context.Context) (string, []string) {
 	return user, groups
 }
 
-func checkAuthenticatedUser(ctx context.Context) error {
-	u, g := authenticatedUserFromContext(ctx)
-	if u == "" {
-		return fmt.Errorf("unauthorized access to tiller")
+// authenticate runs the configured Authenticators, if any, and attaches the
+// resulting UserInfo to the returned context. When no Authenticators are
+// configured it falls back to the legacy AuthProxyEnabled header check so
+// existing reverse-proxy deployments keep working unchanged.
+func (f *ServerOptsFactory) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if len(f.Authenticators) > 0 {
+		u, err := authenticate(ctx, method, f.Authenticators)
+		if err != nil {
+			return nil, err
+		}
+		return withUserInfo(ctx, u), nil
 	}
-	log.Printf("Authenticated as: user=%s, groups=%s", u, strings.Join(g, ","))
-	return nil
-}
-
-func (f *ServerOptsFactory) optionallyCheckAuthenticatedUser(ctx context.Context) error {
-	if f.AuthProxyEnabled {
-		return checkAuthenticatedUser(ctx)
+	if !f.AuthProxyEnabled {
+		return ctx, nil
 	}
-	return nil
+	user, groups := authenticatedUserFromContext(ctx)
+	if user == "" {
+		return nil, fmt.Errorf("unauthorized access to tiller")
+	}
+	return withUserInfo(ctx, &UserInfo{Name: user, Groups: groups}), nil
 }
@@ -0,0 +1,188 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tiller
+
+import (
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/trace/jaeger"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
+)
+
+// TracingConfig configures OpenTelemetry tracing for Tiller's gRPC server.
+type TracingConfig struct {
+	// Enabled turns span export on. When false, a span is still started for
+	// every RPC, but it is never sampled or exported.
+	Enabled bool `yaml:"enabled"`
+	// Exporter selects the span exporter: "otlp" (default) or "jaeger".
+	Exporter string `yaml:"exporter"`
+	// Endpoint is the OTLP collector address or Jaeger agent/collector
+	// endpoint, depending on Exporter.
+	Endpoint string `yaml:"endpoint"`
+	// SampleRatio is the fraction of traces sampled, in [0,1]. Defaults to 1
+	// (sample everything) when unset.
+	SampleRatio float64 `yaml:"sampleRatio"`
+	// ServiceName identifies Tiller in exported spans. Defaults to "tiller".
+	ServiceName string `yaml:"serviceName"`
+}
+
+// NewTracerProvider builds an sdktrace.TracerProvider from cfg, wiring the
+// configured exporter and sampler. Callers are responsible for calling
+// Shutdown on the returned provider during graceful shutdown.
+func (c TracingConfig) NewTracerProvider() (*sdktrace.TracerProvider, error) {
+	if !c.Enabled {
+		return sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.NeverSample())), nil
+	}
+
+	exporter, err := c.newExporter()
+	if err != nil {
+		return nil, err
+	}
+
+	ratio := c.SampleRatio
+	if ratio <= 0 {
+		ratio = 1
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.TraceIDRatioBased(ratio)),
+	), nil
+}
+
+func (c TracingConfig) newExporter() (sdktrace.SpanExporter, error) {
+	switch c.Exporter {
+	case "", "otlp":
+		client := otlptracegrpc.NewClient(otlptracegrpc.WithEndpoint(c.Endpoint), otlptracegrpc.WithInsecure())
+		return otlptrace.New(context.Background(), client)
+	case "jaeger":
+		return jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(c.Endpoint)))
+	default:
+		return nil, fmt.Errorf("tracing: unknown exporter %q", c.Exporter)
+	}
+}
+
+func (c TracingConfig) serviceName() string {
+	if c.ServiceName == "" {
+		return "tiller"
+	}
+	return c.ServiceName
+}
+
+// tracer returns the named tracer for f's TracingConfig. The underlying
+// TracerProvider is built from f.Tracing once, the first time any goroutine
+// calls tracer(), since startSpan is invoked concurrently from every
+// in-flight unary/stream RPC. If building it fails (e.g. a misconfigured
+// exporter), tracer falls back to the ambient global provider so a bad
+// tracing config degrades to a no-op instead of crashing the server.
+func (f *ServerOptsFactory) tracer() trace.Tracer {
+	f.tracerProviderOnce.Do(func() {
+		provider, err := f.Tracing.NewTracerProvider()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "tracing: building tracer provider: %v\n", err)
+			f.tracerProvider = otel.GetTracerProvider()
+			return
+		}
+		f.tracerProvider = provider
+	})
+	return f.tracerProvider.Tracer(f.Tracing.serviceName())
+}
+
+// startSpan begins a server span named after the RPC method, extracting the
+// W3C traceparent carried in incoming gRPC metadata as the parent context.
+func (f *ServerOptsFactory) startSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	carrier := metadataCarrier{ctx: ctx}
+	ctx = propagation.TraceContext{}.Extract(ctx, carrier)
+
+	ctx, span := f.tracer().Start(ctx, method, trace.WithSpanKind(trace.SpanKindServer))
+
+	if v := versionFromContext(ctx); v != "" {
+		span.SetAttributes(attribute.String("helm.client_version", v))
+	}
+
+	return ctx, span
+}
+
+// annotateRelease attaches release-identifying attributes to the current
+// span. The unary interceptor calls this once requestFromMessage has
+// extracted the release name, namespace, and (for InstallRelease/
+// UpdateRelease) chart name/version from the request, so that the RPC's
+// server span records which release it acted on.
+func annotateRelease(ctx context.Context, release, namespace, chartName, chartVersion string) {
+	span := trace.SpanFromContext(ctx)
+	if release != "" {
+		span.SetAttributes(attribute.String("helm.release", release))
+	}
+	if namespace != "" {
+		span.SetAttributes(attribute.String("helm.namespace", namespace))
+	}
+	if chartName != "" {
+		span.SetAttributes(attribute.String("helm.chart.name", chartName))
+	}
+	if chartVersion != "" {
+		span.SetAttributes(attribute.String("helm.chart.version", chartVersion))
+	}
+	if u, ok := UserInfoFromContext(ctx); ok && u != nil {
+		span.SetAttributes(attribute.String("helm.user", u.Name))
+	}
+}
+
+// metadataCarrier adapts incoming gRPC metadata to a propagation.TextMapCarrier
+// so otel's propagators can read the traceparent/tracestate headers.
+type metadataCarrier struct {
+	ctx context.Context
+}
+
+func (m metadataCarrier) Get(key string) string {
+	md, ok := metadata.FromIncomingContext(m.ctx)
+	if !ok {
+		return ""
+	}
+	v := md.Get(key)
+	if len(v) == 0 {
+		return ""
+	}
+	return v[0]
+}
+
+func (m metadataCarrier) Set(key, value string) {
+	// Tiller only needs to extract incoming trace context, never inject it
+	// back into the response, so Set is a no-op.
+}
+
+func (m metadataCarrier) Keys() []string {
+	md, ok := metadata.FromIncomingContext(m.ctx)
+	if !ok {
+		return nil
+	}
+	keys := make([]string, 0, len(md))
+	for k := range md {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+var _ propagation.TextMapCarrier = metadataCarrier{ctx: context.Background()}